@@ -5,15 +5,19 @@ package main
 
 import (
 	"bufio"
-	"crypto/rand"
+	"context"
+	"crypto/sha256"
 	"flag"
 	"fmt"
+	"hash"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
@@ -99,75 +103,230 @@ func parseSize(sizeStr string) (int, error) {
 	}
 }
 
-// createRandomFile creates a random file with the specified parameters.
-func createRandomFile(filePath string, fileSize int, bufferSize int) error {
+// resolveSizeSampler builds the SizeSampler for a run. -size-dist takes
+// precedence; plain -size is sugar for size-dist fixed:<size>.
+func resolveSizeSampler(sizeStr, sizeDistStr string) (SizeSampler, error) {
+	if sizeDistStr != "" {
+		return parseSizeDist(sizeDistStr)
+	}
+	size, err := parseSize(sizeStr)
+	if err != nil || size <= 0 {
+		if err == nil {
+			err = fmt.Errorf("size must be positive")
+		}
+		return nil, err
+	}
+	return fixedSampler{size: size}, nil
+}
+
+// createRandomFile creates a file with content drawn from source. When
+// computeHash is set, it also hashes each chunk with SHA-256 as it's
+// generated - before it reaches the file - so hashing overlaps writing
+// instead of requiring a second read-back pass, and returns the resulting
+// digest for the manifest. direct and preallocate are handled by the
+// fileBackend for the current platform (see file_backend*.go). When direct
+// is set, writes bypass bufio entirely: O_DIRECT/F_NOCACHE require both an
+// aligned buffer and a block-sized transfer length, and routing an
+// already-aligned buffer through bufio.Writer loses that alignment - an
+// exactly-buffer-sized Write only copies into bufio's own unaligned
+// internal buffer instead of reaching the file directly (see
+// bufio.Writer.Write's len(p) > Available() check). The final, possibly
+// partial, chunk is padded up to blockAlignment before the direct write
+// and the file is truncated back to fileSize afterward. When limiters is
+// non-nil, its bandwidth limiter is consulted before each chunk is
+// written.
+func createRandomFile(filePath string, fileSize int, bufferSize int, source ContentSource, computeHash, direct, preallocate bool, limiters *rateLimiters) (string, error) {
 	// Ensure directory exists
 	err := os.MkdirAll(filepath.Dir(filePath), 0755)
 	if err != nil {
-		return fmt.Errorf("error creating directory for file %s: %v", filePath, err)
+		return "", fmt.Errorf("error creating directory for file %s: %v", filePath, err)
 	}
 
 	// Create file
-	file, err := os.Create(filePath)
+	file, err := openFileBackend(filePath, fileSize, direct, preallocate)
 	if err != nil {
-		return fmt.Errorf("error creating file %s: %v", filePath, err)
+		return "", err
 	}
 	defer file.Close()
 
-	// Buffered write with specified buffer size
-	bufWriter := bufio.NewWriterSize(file, bufferSize)
+	var hasher hash.Hash
+	if computeHash {
+		hasher = sha256.New()
+	}
+
+	// zero-fill is sparse-friendly: punch the hole via Truncate instead of
+	// writing zero pages.
+	if _, isZero := source.(zeroSource); isZero {
+		if err := file.Truncate(int64(fileSize)); err != nil {
+			return "", fmt.Errorf("error truncating file %s: %v", filePath, err)
+		}
+		if hasher != nil {
+			zeros := make([]byte, bufferSize)
+			for remaining := fileSize; remaining > 0; {
+				chunkSize := min(bufferSize, remaining)
+				hasher.Write(zeros[:chunkSize])
+				remaining -= chunkSize
+			}
+			return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+		}
+		return "", nil
+	}
 
-	// Generate random data and write to file in chunks
+	// Non-direct writes go through bufio; direct writes go straight to the
+	// file so the aligned buffer from alignedBuffer reaches the O_DIRECT
+	// write call untouched.
+	var bufWriter *bufio.Writer
+	if !direct {
+		bufWriter = bufio.NewWriterSize(file, bufferSize)
+	}
+
+	// Generate content and write to file in chunks
 	remaining := fileSize
-	buffer := make([]byte, bufferSize)
+	buffer := alignedBuffer(bufferSize, direct)
 	for remaining > 0 {
 		chunkSize := bufferSize
 		if remaining < bufferSize {
 			chunkSize = remaining
 		}
-		_, err = rand.Read(buffer[:chunkSize]) // Fill buffer with random bytes
+		err = source.Fill(buffer[:chunkSize])
 		if err != nil {
-			return fmt.Errorf("error generating random data: %v", err)
+			return "", fmt.Errorf("error generating content: %v", err)
+		}
+		if hasher != nil {
+			hasher.Write(buffer[:chunkSize])
+		}
+		if limiters != nil {
+			if err := limiters.waitForBytes(context.Background(), chunkSize); err != nil {
+				return "", fmt.Errorf("rate limiter error for file %s: %v", filePath, err)
+			}
+		}
+		if direct {
+			// bufferSize is always a multiple of blockAlignment (see
+			// getOptimalBufferSize), so only the final, possibly partial,
+			// chunk can be short of a block boundary. Pad it with zeros up
+			// to the next block and truncate the padding back off below.
+			writeSize := chunkSize
+			if rem := writeSize % blockAlignment; rem != 0 {
+				writeSize += blockAlignment - rem
+				clear(buffer[chunkSize:writeSize])
+			}
+			_, err = file.Write(buffer[:writeSize])
+		} else {
+			_, err = bufWriter.Write(buffer[:chunkSize])
 		}
-		_, err = bufWriter.Write(buffer[:chunkSize])
 		if err != nil {
-			return fmt.Errorf("error writing to file %s: %v", filePath, err)
+			return "", fmt.Errorf("error writing to file %s: %v", filePath, err)
 		}
 		remaining -= chunkSize
 	}
 
-	// Flush buffer
-	err = bufWriter.Flush()
-	if err != nil {
-		return fmt.Errorf("error flushing buffer to file %s: %v", filePath, err)
+	if direct {
+		if fileSize%blockAlignment != 0 {
+			if err := file.Truncate(int64(fileSize)); err != nil {
+				return "", fmt.Errorf("error truncating padded file %s: %v", filePath, err)
+			}
+		}
+	} else if err := bufWriter.Flush(); err != nil {
+		return "", fmt.Errorf("error flushing buffer to file %s: %v", filePath, err)
 	}
 
-	return nil
+	if hasher != nil {
+		return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+	}
+	return "", nil
 }
 
-// worker represents a worker for creating random files.
-func worker(id int, jobs <-chan string, fileSize int, bufferSize int, wg *sync.WaitGroup, progress chan<- int64) {
+// worker represents a worker for creating random files. It owns a single
+// ContentSource instance and a single size-dist rng for its entire
+// lifetime so generator state (e.g. PRNG state) is never shared with other
+// workers. When manifest is non-nil, each created file's record is sent to
+// it for the manifest writer goroutine to persist. When limiters is
+// non-nil, each file waits for an IOPS token before it starts; thinkTime,
+// if positive, sleeps after each file to simulate application pauses.
+// failures is incremented for every file the worker fails to create, so
+// the caller can tell a run that logged errors from one that actually
+// succeeded.
+func worker(id int, jobs <-chan string, sizeSampler SizeSampler, bufferSize int, contentSpec string, seed int64, manifest chan<- manifestEntry, direct, preallocate bool, limiters *rateLimiters, thinkTime time.Duration, wg *sync.WaitGroup, progress chan<- int64, failures *atomic.Int64) {
 	defer wg.Done()
+	source, err := newContentSource(contentSpec, seed, id)
+	if err != nil {
+		fmt.Printf("Worker %d: %v\n", id, err)
+		failures.Add(1)
+		return
+	}
+	rng := rand.New(rand.NewSource(seed + int64(id)))
 	for filePath := range jobs {
+		if limiters != nil {
+			if err := limiters.waitForFile(context.Background()); err != nil {
+				fmt.Printf("Worker %d: rate limiter error: %v\n", id, err)
+				failures.Add(1)
+				continue
+			}
+		}
+		fileSize := sizeSampler.Sample(rng)
 		start := time.Now()
-		err := createRandomFile(filePath, fileSize, bufferSize)
+		digest, err := createRandomFile(filePath, fileSize, bufferSize, source, manifest != nil, direct, preallocate, limiters)
 		if err != nil {
 			fmt.Printf("Worker %d: %v\n", id, err)
+			failures.Add(1)
+		} else if manifest != nil {
+			manifest <- manifestEntry{Path: filePath, Size: int64(fileSize), SHA256: digest, Seed: seed, MTime: time.Now()}
 		}
 		elapsed := time.Since(start)
 		progress <- int64(fileSize) // Send the size of the file through the progress channel
 		progress <- int64(elapsed)  // Send the elapsed time through the progress channel
+		if thinkTime > 0 {
+			time.Sleep(thinkTime)
+		}
 	}
 }
 
-// createRandomDataFiles creates random data files based on the provided parameters.
-func createRandomDataFiles(directory string, startNum, endNum, fileSize, filesPerDir, bufferSize, numWorkers int, noSubdirs bool) {
+// createRandomDataFiles creates random data files based on the provided
+// parameters. When manifestPath is set, a per-file manifest entry
+// ({path, size, sha256, seed, mtime}) is streamed to manifestPath as files
+// are produced. When resume is also set and manifestPath already exists,
+// file numbers it already lists are verified in place instead of being
+// recreated. direct opens files with O_DIRECT/F_NOCACHE to bypass the page
+// cache, and preallocate reserves each file's space up front via
+// fallocate/F_PREALLOCATE - both useful when characterizing raw storage
+// throughput rather than RAM. limiters and thinkTime shape the write rate
+// for sustained-load tests; if duration is positive, file numbers run from
+// startNum until the wall-clock deadline instead of stopping at endNum.
+// It returns the highest file number it produced - the caller-supplied
+// endNum under a fixed range, or the actual number reached when duration
+// cut the run short - and the number of files that failed, whether that's
+// a creation failure or a -resume verification finding corruption.
+func createRandomDataFiles(directory string, startNum, endNum int, sizeSampler SizeSampler, filesPerDir, bufferSize, numWorkers int, noSubdirs bool, contentSpec string, seed int64, manifestPath string, resume, direct, preallocate bool, limiters *rateLimiters, thinkTime, duration time.Duration) (lastFileNum int, failedFiles int64) {
 	jobs := make(chan string, numWorkers*2)
 	progress := make(chan int64, numWorkers*2)
 	var wg sync.WaitGroup
+	var failures atomic.Int64
+	var highestNum atomic.Int64
+	highestNum.Store(int64(startNum - 1))
+
+	var manifestChan chan manifestEntry
+	var manifestDone <-chan error
+	existing := map[string]manifestEntry{}
+	if manifestPath != "" {
+		if resume {
+			loaded, err := loadManifest(manifestPath)
+			if err != nil {
+				fmt.Printf("Error loading manifest for resume: %v\n", err)
+			} else {
+				existing = loaded
+			}
+		}
+		manifestChan = make(chan manifestEntry, numWorkers*2)
+		manifestDone = writeManifest(manifestPath, manifestChan, resume)
+	}
 
 	startTime := time.Now()
 	totalFiles := endNum - startNum + 1
+	var deadline time.Time
+	if duration > 0 {
+		totalFiles = 0 // unknown ahead of time; the run stops on the clock, not a count
+		deadline = startTime.Add(duration)
+	}
 
 	fmt.Printf("Starting file creation with %d workers at %s\n", numWorkers, startTime.Format(time.RFC3339))
 
@@ -188,7 +347,11 @@ func createRandomDataFiles(directory string, startNum, endNum, fileSize, filesPe
 				totalTime += timeTaken
 				completed++
 				mbRate := float64(totalBytes) / (1024 * 1024) / (float64(totalTime) / float64(time.Second))
-				fmt.Printf("\rProgress: %d/%d files created in directory %s (%.2f%%) - Bit Rate: %.2f MBps", completed, totalFiles, filepath.Dir(directory), float64(completed)/float64(totalFiles)*100, mbRate)
+				if duration > 0 {
+					fmt.Printf("\rProgress: %d files created in directory %s, %s elapsed of %s - Bit Rate: %.2f MBps", completed, filepath.Dir(directory), time.Since(startTime).Round(time.Second), duration, mbRate)
+				} else {
+					fmt.Printf("\rProgress: %d/%d files created in directory %s (%.2f%%) - Bit Rate: %.2f MBps", completed, totalFiles, filepath.Dir(directory), float64(completed)/float64(totalFiles)*100, mbRate)
+				}
 			}
 		}
 	}()
@@ -196,32 +359,46 @@ func createRandomDataFiles(directory string, startNum, endNum, fileSize, filesPe
 	// Start workers
 	for w := 0; w < numWorkers; w++ {
 		wg.Add(1)
-		go worker(w, jobs, fileSize, bufferSize, &wg, progress)
+		go worker(w, jobs, sizeSampler, bufferSize, contentSpec, seed, manifestChan, direct, preallocate, limiters, thinkTime, &wg, progress, &failures)
 	}
 
-	// Generate file paths and send them to workers
+	// Generate file paths and send them to workers, skipping and instead
+	// verifying files -resume already found in the manifest. In -duration
+	// mode, file numbers keep incrementing past endNum until the deadline.
 	go func() {
 		defer close(jobs)
-		for i := startNum; i <= endNum; i++ {
-			var filePath string
-			if noSubdirs {
-				filePath = fmt.Sprintf("%s/file_%d.bin", directory, i)
-			} else {
-				subdirNum := i / filesPerDir
-				subDir := fmt.Sprintf("%s/subdir_%d", directory, subdirNum)
-				filePath = fmt.Sprintf("%s/file_%d.bin", subDir, i)
+		for i := startNum; duration > 0 || i <= endNum; i++ {
+			if duration > 0 && time.Now().After(deadline) {
+				return
+			}
+			highestNum.Store(int64(i))
+			path := filePathFor(directory, i, filesPerDir, noSubdirs)
+			if entry, ok := existing[path]; ok {
+				if err := verifyAgainstManifest(path, entry); err != nil {
+					fmt.Printf("\nResume verification failed: %v\n", err)
+					failures.Add(1)
+				}
+				continue
 			}
-			jobs <- filePath
+			jobs <- path
 		}
 	}()
 
 	wg.Wait()
+	if manifestChan != nil {
+		close(manifestChan)
+		if err := <-manifestDone; err != nil {
+			fmt.Printf("\nError writing manifest %s: %v\n", manifestPath, err)
+		}
+	}
 	close(progress)
 
 	endTime := time.Now()
 	time.Sleep(1 * time.Second)
 	fmt.Printf("\nFinished file creation at %s\n", endTime.Format(time.RFC3339))
 	fmt.Printf("Total time taken: %s\n", endTime.Sub(startTime))
+
+	return int(highestNum.Load()), failures.Load()
 }
 
 // Add this function after the imports
@@ -260,10 +437,24 @@ func main() {
 	directoryPtr := flag.String("directory", "", "Root Directory where sub-directories and files will be created")
 	startNumPtr := flag.Int("start", 0, "Starting number of files")
 	endNumPtr := flag.Int("end", 0, "Ending number of files")
-	sizePtr := flag.String("size", "", "Size of each file. Supported formats are B, KB, MB, GB (e.g., '1 GB')")
+	sizePtr := flag.String("size", "", "Size of each file. Supported formats are B, KB, MB, GB (e.g., '1 GB'). Shorthand for -size-dist fixed:<size>")
+	sizeDistPtr := flag.String("size-dist", "", "Size distribution: fixed:<size>, uniform:<min>-<max>, normal:mean=...,stddev=..., lognormal:mu=...,sigma=..., zipf:s=...,min=...,max=..., or histogram:<size>=<weight>,...")
 	filesPerDirPtr := flag.Int("files-per-dir", defaultFilesPerDir, "Number of files per subdirectory")
 	numWorkersPtr := flag.Int("workers", getOptimalWorkerCount(), "Number of workers - Default is number of CPUs")
 	noSubdirsPtr := flag.Bool("no-subdirs", false, "Disable the creation of subdirectories")
+	contentPtr := flag.String("content", "crypto", "Content source: crypto, prng, zero, text, or pattern:<hex>")
+	seedPtr := flag.Int64("seed", time.Now().UnixNano(), "Seed for the prng content source (ignored by other sources)")
+	modePtr := flag.String("mode", "write", "Mode: write, read, mixed, or verify")
+	jsonPtr := flag.Bool("json", false, "Emit read/mixed benchmark results as JSON instead of a table")
+	manifestPtr := flag.String("manifest", "", "Path to a newline-delimited JSON manifest of {path,size,sha256,seed,mtime} to write (write/mixed) or check against (verify)")
+	resumePtr := flag.Bool("resume", false, "With -manifest, skip and instead verify file numbers already recorded in the manifest")
+	directPtr := flag.Bool("direct", false, "Open files with O_DIRECT (Linux) / F_NOCACHE (Darwin) to bypass the page cache")
+	preallocatePtr := flag.Bool("preallocate", false, "Preallocate each file's space with fallocate/F_PREALLOCATE before writing")
+	maxMBpsPtr := flag.Float64("max-mbps", 0, "Cap aggregate write bandwidth in MB/s (0 disables the limit)")
+	maxIOPSPtr := flag.Int("max-iops", 0, "Cap file creation rate in files/sec (0 disables the limit)")
+	rampUpPtr := flag.Duration("ramp-up", 0, "Smoothly raise -max-mbps/-max-iops from near zero to their limit over this duration (e.g. '30s')")
+	thinkTimePtr := flag.Duration("think-time", 0, "Sleep this long after each file to simulate application pauses (e.g. '10ms')")
+	durationPtr := flag.Duration("duration", 0, "Run until this much wall-clock time has elapsed instead of stopping at -end (e.g. '10m')")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "FileForge v%s - High Performance File Generator\n\n", getVersion())
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
@@ -273,19 +464,69 @@ func main() {
 	}
 	flag.Parse()
 
-	// Check if required options are provided
-	if *directoryPtr == "" || *startNumPtr == 0 || *endNumPtr == 0 || *sizePtr == "" {
+	// Check if required options are provided. -size/-size-dist is only
+	// needed when writing new files; read/verify operate on files that
+	// already exist. -end is likewise not required when -duration bounds
+	// the run instead.
+	needsSize := *modePtr == "write" || *modePtr == "mixed"
+	needsEnd := *durationPtr == 0
+	if *directoryPtr == "" || *startNumPtr == 0 || (needsEnd && *endNumPtr == 0) || (needsSize && *sizePtr == "" && *sizeDistPtr == "") {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Parse file size
-	fileSize, err := parseSize(*sizePtr)
-	if err != nil || fileSize <= 0 {
-		fmt.Fprintf(os.Stderr, "Error parsing file size: %v\n", err)
+	limiters := newRateLimiters(*maxMBpsPtr, *maxIOPSPtr, *rampUpPtr, bufferSize)
+
+	switch *modePtr {
+	case "write":
+		sizeSampler, err := resolveSizeSampler(*sizePtr, *sizeDistPtr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing file size: %v\n", err)
+			os.Exit(1)
+		}
+		_, failed := createRandomDataFiles(*directoryPtr, *startNumPtr, *endNumPtr, sizeSampler, *filesPerDirPtr, bufferSize, *numWorkersPtr, *noSubdirsPtr, *contentPtr, *seedPtr, *manifestPtr, *resumePtr, *directPtr, *preallocatePtr, limiters, *thinkTimePtr, *durationPtr)
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d file(s) failed during write phase\n", failed)
+			os.Exit(1)
+		}
+
+	case "mixed":
+		sizeSampler, err := resolveSizeSampler(*sizePtr, *sizeDistPtr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing file size: %v\n", err)
+			os.Exit(1)
+		}
+		lastNum, failed := createRandomDataFiles(*directoryPtr, *startNumPtr, *endNumPtr, sizeSampler, *filesPerDirPtr, bufferSize, *numWorkersPtr, *noSubdirsPtr, *contentPtr, *seedPtr, *manifestPtr, *resumePtr, *directPtr, *preallocatePtr, limiters, *thinkTimePtr, *durationPtr)
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d file(s) failed during write phase\n", failed)
+			os.Exit(1)
+		}
+		// Under -duration, endNumPtr is left at its zero default since -end
+		// isn't required; read back the range createRandomDataFiles actually
+		// produced instead, or the verify pass below would run over zero files.
+		readEnd := *endNumPtr
+		if *durationPtr > 0 {
+			readEnd = lastNum
+		}
+		if err := runReadBenchmark(*directoryPtr, *startNumPtr, readEnd, *filesPerDirPtr, bufferSize, *noSubdirsPtr, *jsonPtr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running read benchmark: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "read":
+		if err := runReadBenchmark(*directoryPtr, *startNumPtr, *endNumPtr, *filesPerDirPtr, bufferSize, *noSubdirsPtr, *jsonPtr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running read benchmark: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "verify":
+		if err := runVerify(*directoryPtr, *startNumPtr, *endNumPtr, *filesPerDirPtr, *noSubdirsPtr, *manifestPtr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running verify: %v\n", err)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown mode %q: expected write, read, mixed, or verify\n", *modePtr)
 		os.Exit(1)
 	}
-
-	// Start file creation process
-	createRandomDataFiles(*directoryPtr, *startNumPtr, *endNumPtr, fileSize, *filesPerDirPtr, bufferSize, *numWorkersPtr, *noSubdirsPtr)
 }
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"time"
+)
+
+// filePathFor returns the path for file number i, matching the layout used
+// when the files were created (see createRandomDataFiles).
+func filePathFor(directory string, i, filesPerDir int, noSubdirs bool) string {
+	if noSubdirs {
+		return fmt.Sprintf("%s/file_%d.bin", directory, i)
+	}
+	subdirNum := i / filesPerDir
+	return fmt.Sprintf("%s/subdir_%d/file_%d.bin", directory, subdirNum, i)
+}
+
+// latencyStats summarizes a batch of per-op latencies alongside aggregate
+// throughput, in the style of the seaweedfs and seek-tester benchmarks.
+type latencyStats struct {
+	Ops        int           `json:"ops"`
+	TotalBytes int64         `json:"total_bytes"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	P50        time.Duration `json:"p50_ns"`
+	P90        time.Duration `json:"p90_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	P999       time.Duration `json:"p999_ns"`
+}
+
+// throughputMBps returns the aggregate throughput in MB/s.
+func (s latencyStats) throughputMBps() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.TotalBytes) / (1024 * 1024) / s.Elapsed.Seconds()
+}
+
+// percentile returns the p-th percentile (0-100) of a sorted duration slice.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p / 100 * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// newLatencyStats builds a latencyStats from raw per-op latencies.
+func newLatencyStats(latencies []time.Duration, totalBytes int64, elapsed time.Duration) latencyStats {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return latencyStats{
+		Ops:        len(latencies),
+		TotalBytes: totalBytes,
+		Elapsed:    elapsed,
+		P50:        percentile(sorted, 50),
+		P90:        percentile(sorted, 90),
+		P99:        percentile(sorted, 99),
+		P999:       percentile(sorted, 99.9),
+	}
+}
+
+// printTable prints stats as a human-readable table.
+func (s latencyStats) printTable(label string) {
+	fmt.Printf("\n%s results:\n", label)
+	fmt.Printf("  ops: %d, total: %s, elapsed: %s, throughput: %.2f MBps\n",
+		s.Ops, humanReadableSize(int(s.TotalBytes)), s.Elapsed.Round(time.Millisecond), s.throughputMBps())
+	fmt.Printf("  latency p50: %s, p90: %s, p99: %s, p999: %s\n",
+		s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.P999.Round(time.Microsecond))
+}
+
+// benchmarkRead measures read throughput and per-op latency across the
+// files in [startNum, endNum]. When random is true, each op seeks to a
+// random offset within the file and reads bufferSize bytes instead of
+// reading sequentially from the start.
+func benchmarkRead(directory string, startNum, endNum, filesPerDir, bufferSize int, noSubdirs bool, random bool) (latencyStats, error) {
+	var latencies []time.Duration
+	var totalBytes int64
+	buf := make([]byte, bufferSize)
+	start := time.Now()
+
+	for i := startNum; i <= endNum; i++ {
+		path := filePathFor(directory, i, filesPerDir, noSubdirs)
+		file, err := os.Open(path)
+		if err != nil {
+			return latencyStats{}, fmt.Errorf("error opening file %s for read benchmark: %v", path, err)
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return latencyStats{}, fmt.Errorf("error statting file %s: %v", path, err)
+		}
+
+		opStart := time.Now()
+		var n int
+		if random && info.Size() > int64(len(buf)) {
+			offset := rand.Int64N(info.Size() - int64(len(buf)))
+			n, err = file.ReadAt(buf, offset)
+		} else {
+			n, err = file.ReadAt(buf[:min(len(buf), int(info.Size()))], 0)
+		}
+		latencies = append(latencies, time.Since(opStart))
+		file.Close()
+
+		if err != nil && err != io.EOF {
+			return latencyStats{}, fmt.Errorf("error reading file %s: %v", path, err)
+		}
+		totalBytes += int64(n)
+	}
+
+	return newLatencyStats(latencies, totalBytes, time.Since(start)), nil
+}
+
+// verifyFile hashes a file's contents with SHA-256 and returns the digest.
+func verifyFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening file %s for verification: %v", path, err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("error hashing file %s: %v", path, err)
+	}
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// runVerify hashes every file in [startNum, endNum]. If manifestPath is
+// set, each digest is cross-checked against the matching manifest entry
+// and mismatches are reported instead of just printing digests.
+func runVerify(directory string, startNum, endNum, filesPerDir int, noSubdirs bool, manifestPath string) error {
+	var manifest map[string]manifestEntry
+	if manifestPath != "" {
+		loaded, err := loadManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		manifest = loaded
+	}
+
+	mismatches := 0
+	for i := startNum; i <= endNum; i++ {
+		path := filePathFor(directory, i, filesPerDir, noSubdirs)
+		digest, err := verifyFile(path)
+		if err != nil {
+			return err
+		}
+
+		entry, hasEntry := manifest[path]
+		switch {
+		case manifest == nil:
+			fmt.Printf("%s  %s\n", digest, path)
+		case !hasEntry:
+			fmt.Printf("%s  %s  (not in manifest)\n", digest, path)
+		case digest != entry.SHA256:
+			fmt.Printf("%s  %s  MISMATCH (manifest has %s)\n", digest, path, entry.SHA256)
+			mismatches++
+		default:
+			fmt.Printf("%s  %s  OK\n", digest, path)
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("%d file(s) failed verification against manifest", mismatches)
+	}
+	return nil
+}
+
+// benchResult is the JSON-serializable form of a bench run, emitted when
+// -json is passed.
+type benchResult struct {
+	Mode       string        `json:"mode"`
+	Sequential *latencyStats `json:"sequential,omitempty"`
+	Random     *latencyStats `json:"random,omitempty"`
+}
+
+func (r benchResult) printJSON() {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		fmt.Printf("error marshaling bench result: %v\n", err)
+		return
+	}
+	fmt.Println(string(data))
+}
+
+// runReadBenchmark measures sequential and random-seek read throughput over
+// the given file range and reports the results as a table or, if asJSON is
+// set, as JSON.
+func runReadBenchmark(directory string, startNum, endNum, filesPerDir, bufferSize int, noSubdirs, asJSON bool) error {
+	seq, err := benchmarkRead(directory, startNum, endNum, filesPerDir, bufferSize, noSubdirs, false)
+	if err != nil {
+		return err
+	}
+	rnd, err := benchmarkRead(directory, startNum, endNum, filesPerDir, bufferSize, noSubdirs, true)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		benchResult{Mode: "read", Sequential: &seq, Random: &rnd}.printJSON()
+		return nil
+	}
+	seq.printTable("Sequential read")
+	rnd.printTable("Random-seek read")
+	return nil
+}
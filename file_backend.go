@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"unsafe"
+)
+
+// blockAlignment is the buffer alignment assumed for O_DIRECT / F_NOCACHE
+// writes. 4096 bytes covers every common disk and SSD sector/page size;
+// a misaligned buffer on a device that needs a larger alignment simply
+// falls back to the kernel's buffered slow path rather than failing.
+const blockAlignment = 4096
+
+// fileBackend abstracts the platform-specific half of opening a file for
+// -direct/-preallocate I/O, so that logic is testable in isolation and
+// lives behind build tags (file_backend_<os>.go) instead of inside
+// createRandomFile.
+//
+// openDirect and preallocateFile are implemented per-platform.
+
+// openFileBackend creates filePath for writing, applying -direct and
+// -preallocate as requested.
+func openFileBackend(filePath string, fileSize int, direct, preallocate bool) (*os.File, error) {
+	file, err := openDirect(filePath, direct)
+	if err != nil {
+		return nil, err
+	}
+	if preallocate {
+		if err := preallocateFile(file, fileSize); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+// alignedBuffer returns a bufferSize-byte slice. When direct is true, the
+// slice is aligned to blockAlignment so it's safe to use with O_DIRECT /
+// F_NOCACHE I/O; otherwise it's a plain, unaligned allocation.
+func alignedBuffer(bufferSize int, direct bool) []byte {
+	if !direct {
+		return make([]byte, bufferSize)
+	}
+	buf := make([]byte, bufferSize+blockAlignment)
+	offset := int(-uintptr(unsafe.Pointer(&buf[0])) & (blockAlignment - 1))
+	return buf[offset : offset+bufferSize : offset+bufferSize]
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// openDirect opens filePath for writing, adding O_DIRECT when direct is
+// true so the page cache is bypassed - needed to characterize raw storage
+// throughput rather than RAM on machines with large caches.
+func openDirect(filePath string, direct bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if direct {
+		flags |= syscall.O_DIRECT
+	}
+	file, err := os.OpenFile(filePath, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %v", filePath, err)
+	}
+	return file, nil
+}
+
+// preallocateFile calls fallocate(2) to reserve fileSize bytes up front so
+// the filesystem lays out contiguous extents instead of fragmenting as
+// writes trickle in.
+func preallocateFile(file *os.File, fileSize int) error {
+	if err := syscall.Fallocate(int(file.Fd()), 0, 0, int64(fileSize)); err != nil {
+		return fmt.Errorf("error preallocating file %s: %v", file.Name(), err)
+	}
+	return nil
+}
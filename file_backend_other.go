@@ -0,0 +1,24 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// openDirect opens filePath for writing. Direct I/O has no portable
+// equivalent outside Linux/Darwin, so -direct is a no-op on this platform.
+func openDirect(filePath string, direct bool) (*os.File, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %v", filePath, err)
+	}
+	return file, nil
+}
+
+// preallocateFile is a no-op on platforms without fallocate/F_PREALLOCATE;
+// the file simply grows as it's written.
+func preallocateFile(file *os.File, fileSize int) error {
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// manifestEntry records what createRandomDataFiles wrote for one file, so a
+// later run can detect corruption on the storage under test or resume an
+// interrupted multi-file job.
+type manifestEntry struct {
+	Path   string    `json:"path"`
+	Size   int64     `json:"size"`
+	SHA256 string    `json:"sha256"`
+	Seed   int64     `json:"seed"`
+	MTime  time.Time `json:"mtime"`
+}
+
+// writeManifest consumes entries as they arrive from the workers and
+// appends them to path as newline-delimited JSON, one line per file. It
+// runs on its own goroutine so manifest I/O never blocks file creation.
+// When resume is true, entries are appended to an existing manifest;
+// otherwise the manifest is truncated and rebuilt from scratch.
+func writeManifest(path string, entries <-chan manifestEntry, resume bool) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		flags := os.O_CREATE | os.O_WRONLY
+		if resume {
+			flags |= os.O_APPEND
+		} else {
+			flags |= os.O_TRUNC
+		}
+		file, err := os.OpenFile(path, flags, 0644)
+		if err != nil {
+			for range entries {
+				// drain so workers never block on a full channel
+			}
+			done <- fmt.Errorf("error opening manifest %s: %v", path, err)
+			return
+		}
+		defer file.Close()
+
+		w := bufio.NewWriter(file)
+		var writeErr error
+		for entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				writeErr = err
+				continue
+			}
+			if _, err := w.Write(append(data, '\n')); err != nil {
+				writeErr = err
+			}
+		}
+		if err := w.Flush(); err != nil && writeErr == nil {
+			writeErr = err
+		}
+		done <- writeErr
+	}()
+	return done
+}
+
+// loadManifest reads an existing manifest and indexes its entries by path,
+// for -resume to consult. A missing manifest is not an error: it just
+// means there's nothing to resume from.
+func loadManifest(path string) (map[string]manifestEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]manifestEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening manifest %s: %v", path, err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]manifestEntry)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry manifestEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error parsing manifest %s: %v", path, err)
+		}
+		entries[entry.Path] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading manifest %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// verifyAgainstManifest re-hashes path and compares the digest against
+// entry, the record -resume found for it.
+func verifyAgainstManifest(path string, entry manifestEntry) error {
+	digest, err := verifyFile(path)
+	if err != nil {
+		return err
+	}
+	if digest != entry.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: manifest has %s, file has %s", path, entry.SHA256, digest)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiters bundles the token buckets that shape a write-mode run:
+// -max-mbps caps aggregate write bandwidth and -max-iops caps the file
+// creation rate. -ramp-up smoothly raises both from near zero to their
+// configured limit over rampUp instead of bursting at full rate from the
+// first file, which better matches how a real workload ramps in.
+type rateLimiters struct {
+	bandwidth      *rate.Limiter
+	iops           *rate.Limiter
+	maxBytesPerSec float64
+	maxIOPS        float64
+	rampUp         time.Duration
+	start          time.Time
+}
+
+// newRateLimiters builds the limiters for a run. maxMBps/maxIOPS of 0
+// disable the corresponding limiter. bufferSize sizes the bandwidth
+// limiter's burst so a single chunk write is never rejected as exceeding
+// the bucket.
+func newRateLimiters(maxMBps float64, maxIOPS int, rampUp time.Duration, bufferSize int) *rateLimiters {
+	rl := &rateLimiters{rampUp: rampUp, start: time.Now()}
+	if maxMBps > 0 {
+		rl.maxBytesPerSec = maxMBps * 1024 * 1024
+		burst := int(rl.maxBytesPerSec)
+		if burst < bufferSize {
+			burst = bufferSize
+		}
+		rl.bandwidth = rate.NewLimiter(rate.Limit(rl.maxBytesPerSec), burst)
+	}
+	if maxIOPS > 0 {
+		rl.maxIOPS = float64(maxIOPS)
+		rl.iops = rate.NewLimiter(rate.Limit(rl.maxIOPS), maxIOPS)
+	}
+	return rl
+}
+
+// rampFactor returns the fraction (0-1] of the configured limits that
+// should be in effect right now, linearly increasing across rampUp.
+func (rl *rateLimiters) rampFactor() float64 {
+	if rl.rampUp <= 0 {
+		return 1
+	}
+	elapsed := time.Since(rl.start)
+	if elapsed >= rl.rampUp {
+		return 1
+	}
+	factor := float64(elapsed) / float64(rl.rampUp)
+	if factor < 0.01 {
+		factor = 0.01 // never fully stall the bucket during ramp-up
+	}
+	return factor
+}
+
+// applyRampUp adjusts the limiters' rates to the current point in the
+// ramp-up window. It's cheap to call before every wait.
+func (rl *rateLimiters) applyRampUp() {
+	if rl.rampUp <= 0 {
+		return
+	}
+	factor := rl.rampFactor()
+	if rl.bandwidth != nil {
+		rl.bandwidth.SetLimit(rate.Limit(rl.maxBytesPerSec * factor))
+	}
+	if rl.iops != nil {
+		rl.iops.SetLimit(rate.Limit(rl.maxIOPS * factor))
+	}
+}
+
+// waitForFile blocks, if needed, until the IOPS limiter allows one more
+// file to start.
+func (rl *rateLimiters) waitForFile(ctx context.Context) error {
+	if rl.iops == nil {
+		return nil
+	}
+	rl.applyRampUp()
+	return rl.iops.Wait(ctx)
+}
+
+// waitForBytes blocks, if needed, until the bandwidth limiter allows n more
+// bytes to be written.
+func (rl *rateLimiters) waitForBytes(ctx context.Context, n int) error {
+	if rl.bandwidth == nil {
+		return nil
+	}
+	rl.applyRampUp()
+	return rl.bandwidth.WaitN(ctx, n)
+}
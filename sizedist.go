@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SizeSampler draws a file size, in bytes, from some distribution. Each
+// worker calls Sample once per file so that real filesystems' skewed
+// file-size distributions can be represented instead of a single fixed
+// size for every file in a run.
+type SizeSampler interface {
+	Sample(rng *rand.Rand) int
+}
+
+// parseSizeDist parses a -size-dist spec into a SizeSampler. Supported
+// forms:
+//
+//	fixed:1MB
+//	uniform:4KB-1MB
+//	normal:mean=512KB,stddev=128KB
+//	lognormal:mu=...,sigma=...
+//	zipf:s=1.2,min=1KB,max=100MB
+//	histogram:1KB=0.5,1MB=0.3,100MB=0.2
+func parseSizeDist(spec string) (SizeSampler, error) {
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid size-dist %q: expected kind:params", spec)
+	}
+
+	switch kind {
+	case "fixed":
+		size, err := parseSize(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed size-dist: %v", err)
+		}
+		return fixedSampler{size: size}, nil
+
+	case "uniform":
+		lo, hi, ok := strings.Cut(rest, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid uniform size-dist %q: expected min-max", rest)
+		}
+		min, err := parseSize(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uniform min: %v", err)
+		}
+		max, err := parseSize(hi)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uniform max: %v", err)
+		}
+		if max < min {
+			return nil, fmt.Errorf("invalid uniform size-dist: max %d is less than min %d", max, min)
+		}
+		return uniformSampler{min: min, max: max}, nil
+
+	case "normal":
+		params, err := parseKVParams(rest)
+		if err != nil {
+			return nil, err
+		}
+		mean, err := parseSize(params["mean"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid normal mean: %v", err)
+		}
+		stddev, err := parseSize(params["stddev"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid normal stddev: %v", err)
+		}
+		return normalSampler{mean: float64(mean), stddev: float64(stddev)}, nil
+
+	case "lognormal":
+		params, err := parseKVParams(rest)
+		if err != nil {
+			return nil, err
+		}
+		mu, err := strconv.ParseFloat(params["mu"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lognormal mu: %v", err)
+		}
+		sigma, err := strconv.ParseFloat(params["sigma"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lognormal sigma: %v", err)
+		}
+		return lognormalSampler{mu: mu, sigma: sigma}, nil
+
+	case "zipf":
+		params, err := parseKVParams(rest)
+		if err != nil {
+			return nil, err
+		}
+		s, err := strconv.ParseFloat(params["s"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zipf s: %v", err)
+		}
+		min, err := parseSize(params["min"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid zipf min: %v", err)
+		}
+		max, err := parseSize(params["max"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid zipf max: %v", err)
+		}
+		return newZipfSampler(s, min, max)
+
+	case "histogram":
+		return parseHistogramSampler(rest)
+
+	default:
+		return nil, fmt.Errorf("unknown size-dist kind %q: expected fixed, uniform, normal, lognormal, zipf, or histogram", kind)
+	}
+}
+
+// parseKVParams parses "k1=v1,k2=v2" into a map.
+func parseKVParams(s string) (map[string]string, error) {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter %q: expected key=value", pair)
+		}
+		params[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return params, nil
+}
+
+// fixedSampler always returns the same size; it's what plain -size maps to.
+type fixedSampler struct {
+	size int
+}
+
+func (f fixedSampler) Sample(rng *rand.Rand) int {
+	return f.size
+}
+
+// uniformSampler draws uniformly from [min, max].
+type uniformSampler struct {
+	min, max int
+}
+
+func (u uniformSampler) Sample(rng *rand.Rand) int {
+	if u.max == u.min {
+		return u.min
+	}
+	return u.min + rng.Intn(u.max-u.min+1)
+}
+
+// normalSampler draws from a normal distribution, clamped to be at least 1
+// byte since negative or zero file sizes don't make sense.
+type normalSampler struct {
+	mean, stddev float64
+}
+
+func (n normalSampler) Sample(rng *rand.Rand) int {
+	size := int(rng.NormFloat64()*n.stddev + n.mean)
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// lognormalSampler draws from a lognormal distribution parameterized by the
+// underlying normal's mu and sigma.
+type lognormalSampler struct {
+	mu, sigma float64
+}
+
+func (l lognormalSampler) Sample(rng *rand.Rand) int {
+	size := int(math.Exp(rng.NormFloat64()*l.sigma + l.mu))
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// zipfSampler draws from a Zipf distribution over [min, max]. rand.Zipf is
+// bound to the *rand.Rand it's constructed with, so a fresh one is built
+// against the caller's rng on every Sample call.
+type zipfSampler struct {
+	s        float64
+	min, max int
+}
+
+func newZipfSampler(s float64, min, max int) (*zipfSampler, error) {
+	if s <= 1 {
+		return nil, fmt.Errorf("invalid zipf s %v: must be > 1", s)
+	}
+	if max < min {
+		return nil, fmt.Errorf("invalid zipf size-dist: max %d is less than min %d", max, min)
+	}
+	return &zipfSampler{s: s, min: min, max: max}, nil
+}
+
+func (z *zipfSampler) Sample(rng *rand.Rand) int {
+	zipf := rand.NewZipf(rng, z.s, 1, uint64(z.max-z.min))
+	return z.min + int(zipf.Uint64())
+}
+
+// histogramBucket is one weighted size in a histogram size-dist.
+type histogramBucket struct {
+	size   int
+	weight float64
+}
+
+// histogramSampler draws a size from a weighted set of discrete sizes.
+type histogramSampler struct {
+	buckets []histogramBucket
+	total   float64
+}
+
+func parseHistogramSampler(spec string) (*histogramSampler, error) {
+	var buckets []histogramBucket
+	var total float64
+	for _, pair := range strings.Split(spec, ",") {
+		sizeStr, weightStr, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid histogram entry %q: expected size=weight", pair)
+		}
+		size, err := parseSize(sizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid histogram size: %v", err)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid histogram weight: %v", err)
+		}
+		buckets = append(buckets, histogramBucket{size: size, weight: weight})
+		total += weight
+	}
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("histogram size-dist must have at least one entry")
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].size < buckets[j].size })
+	return &histogramSampler{buckets: buckets, total: total}, nil
+}
+
+func (h *histogramSampler) Sample(rng *rand.Rand) int {
+	target := rng.Float64() * h.total
+	cumulative := 0.0
+	for _, b := range h.buckets {
+		cumulative += b.weight
+		if target <= cumulative {
+			return b.size
+		}
+	}
+	return h.buckets[len(h.buckets)-1].size
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseSizeDistFixed(t *testing.T) {
+	s, err := parseSizeDist("fixed:1MB")
+	if err != nil {
+		t.Fatalf("parseSizeDist(fixed): %v", err)
+	}
+	got := s.Sample(rand.New(rand.NewSource(1)))
+	if want := 1024 * 1024; got != want {
+		t.Errorf("Sample() = %d, want %d", got, want)
+	}
+}
+
+func TestParseSizeDistUniform(t *testing.T) {
+	s, err := parseSizeDist("uniform:4KB-8KB")
+	if err != nil {
+		t.Fatalf("parseSizeDist(uniform): %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if got := s.Sample(rng); got < 4*1024 || got > 8*1024 {
+			t.Fatalf("Sample() = %d, want in [4096, 8192]", got)
+		}
+	}
+
+	if _, err := parseSizeDist("uniform:8KB-4KB"); err == nil {
+		t.Error("parseSizeDist(uniform) with max < min should have failed")
+	}
+	if _, err := parseSizeDist("uniform:4KB"); err == nil {
+		t.Error("parseSizeDist(uniform) without a '-' should have failed")
+	}
+}
+
+func TestParseSizeDistNormal(t *testing.T) {
+	s, err := parseSizeDist("normal:mean=1MB,stddev=128KB")
+	if err != nil {
+		t.Fatalf("parseSizeDist(normal): %v", err)
+	}
+	if got := s.Sample(rand.New(rand.NewSource(1))); got < 1 {
+		t.Errorf("Sample() = %d, want >= 1", got)
+	}
+}
+
+func TestParseSizeDistLognormal(t *testing.T) {
+	s, err := parseSizeDist("lognormal:mu=10,sigma=1")
+	if err != nil {
+		t.Fatalf("parseSizeDist(lognormal): %v", err)
+	}
+	if got := s.Sample(rand.New(rand.NewSource(1))); got < 1 {
+		t.Errorf("Sample() = %d, want >= 1", got)
+	}
+}
+
+func TestParseSizeDistZipf(t *testing.T) {
+	s, err := parseSizeDist("zipf:s=1.2,min=1KB,max=100MB")
+	if err != nil {
+		t.Fatalf("parseSizeDist(zipf): %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if got := s.Sample(rng); got < 1024 || got > 100*1024*1024 {
+			t.Fatalf("Sample() = %d, want in [1024, 104857600]", got)
+		}
+	}
+
+	if _, err := parseSizeDist("zipf:s=0.5,min=1KB,max=100MB"); err == nil {
+		t.Error("parseSizeDist(zipf) with s <= 1 should have failed")
+	}
+}
+
+func TestParseSizeDistHistogram(t *testing.T) {
+	s, err := parseSizeDist("histogram:1KB=0.5,1MB=0.3,100MB=0.2")
+	if err != nil {
+		t.Fatalf("parseSizeDist(histogram): %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	valid := map[int]bool{1024: true, 1024 * 1024: true, 100 * 1024 * 1024: true}
+	for i := 0; i < 100; i++ {
+		if got := s.Sample(rng); !valid[got] {
+			t.Fatalf("Sample() = %d, want one of the histogram's bucket sizes", got)
+		}
+	}
+
+	if _, err := parseSizeDist("histogram:"); err == nil {
+		t.Error("parseSizeDist(histogram) with no entries should have failed")
+	}
+}
+
+func TestParseSizeDistUnknownKind(t *testing.T) {
+	if _, err := parseSizeDist("bogus:1MB"); err == nil {
+		t.Error("parseSizeDist with an unknown kind should have failed")
+	}
+}
+
+func TestParseSizeDistMissingColon(t *testing.T) {
+	if _, err := parseSizeDist("1MB"); err == nil {
+		t.Error("parseSizeDist without a kind:params separator should have failed")
+	}
+}